@@ -0,0 +1,166 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/api/v1"
+)
+
+// StrategyName identifies one of the descheduler's built-in strategies.
+type StrategyName string
+
+// DeschedulerStrategy is the configuration for a single strategy.
+type DeschedulerStrategy struct {
+	Enabled bool
+	Params  StrategyParameters
+}
+
+// Percentage is a value in the range [0, 100].
+type Percentage float64
+
+// ResourceThresholds maps a resource name to a percentage of that resource's node capacity.
+type ResourceThresholds map[v1.ResourceName]Percentage
+
+// StrategyParameters holds the parameters understood by one or more strategies. Strategies
+// ignore the params that don't apply to them.
+type StrategyParameters struct {
+	NodeResourceUtilizationThresholds NodeResourceUtilizationThresholds
+}
+
+// NodeResourceUtilizationThresholds configures the LowNodeUtilization strategy.
+type NodeResourceUtilizationThresholds struct {
+	Thresholds       ResourceThresholds
+	TargetThresholds ResourceThresholds
+	NumberOfNodes    int
+
+	// UsageSource configures how node and pod resource usage is measured when classifying
+	// nodes and sizing evictions. When nil, usage is derived from summed pod resource
+	// requests (the historical behavior).
+	UsageSource *UsageSource
+
+	// SimulateScheduling, when true, projects each candidate pod's usage onto the best-fit
+	// low node before evicting it, and skips the eviction if no low node could absorb the pod
+	// without crossing Thresholds. Defaults to true when nil.
+	SimulateScheduling *bool
+
+	// NodeFit, when true, skips evicting a pod unless at least one low node would actually
+	// accept it (nodeSelector, node affinity, taints/tolerations, and resource fit). This
+	// prevents evicting strictly-pinned pods that would just return to the same node.
+	NodeFit bool
+
+	// ResourceWeights weighs each configured resource (Thresholds/TargetThresholds key) when
+	// ranking target nodes for eviction, so e.g. memory pressure can be emphasized over cpu.
+	// A resource with no entry defaults to a weight of 1.
+	ResourceWeights map[v1.ResourceName]float64
+
+	// NodePools partitions nodes into independently-rebalanced groups (e.g. a GPU pool, a spot
+	// pool, a prod pool) instead of classifying the whole cluster against one set of
+	// thresholds. Nodes matching no pool's Selector fall back to this struct's own
+	// Thresholds/TargetThresholds/NumberOfNodes/ResourceWeights/UsageSource.
+	NodePools []NodePoolSpec
+
+	// PodEvictionOrder selects how candidate pods on a target node are ordered for eviction.
+	// When nil, QoSThenPriorityOrder is used: pods are evicted BestEffort first, then Burstable,
+	// then Guaranteed (the historical class order), with Burstable and Guaranteed pods each
+	// sorted by ascending priority.
+	PodEvictionOrder *PodEvictionOrder
+}
+
+// PodEvictionOrderType names a pluggable pod-eviction ordering.
+type PodEvictionOrderType string
+
+const (
+	// QoSThenPriorityOrder evicts BestEffort pods first, then Burstable pods by ascending
+	// priority, then Guaranteed pods by ascending priority.
+	QoSThenPriorityOrder PodEvictionOrderType = "QoSThenPriority"
+	// PriorityOnlyOrder evicts pods in strict ascending priority order, regardless of QoS
+	// class, mirroring the kubelet's node-pressure eviction order.
+	PriorityOnlyOrder PodEvictionOrderType = "PriorityOnly"
+	// YoungestFirstOrder evicts the most recently created pods first.
+	YoungestFirstOrder PodEvictionOrderType = "YoungestFirst"
+	// CompositeOrder evicts pods by a weighted combination of priority and age.
+	CompositeOrder PodEvictionOrderType = "Composite"
+)
+
+// PodEvictionOrder configures which pod-eviction ordering LowNodeUtilization uses.
+type PodEvictionOrder struct {
+	Type PodEvictionOrderType
+
+	// CompositeWeights weighs priority vs. age when Type is CompositeOrder. Defaults to equal
+	// weighting (1, 1) when nil.
+	CompositeWeights *CompositeWeights
+}
+
+// CompositeWeights weighs priority and age for PodEvictionOrder's CompositeOrder.
+type CompositeWeights struct {
+	Priority float64
+	Age      float64
+}
+
+// NodePoolSpec configures rebalancing for one node pool within LowNodeUtilization.
+type NodePoolSpec struct {
+	// Name identifies the pool in logs.
+	Name string
+
+	// Selector matches the nodes belonging to this pool.
+	Selector metav1.LabelSelector
+
+	Thresholds       ResourceThresholds
+	TargetThresholds ResourceThresholds
+	NumberOfNodes    int
+	ResourceWeights  map[v1.ResourceName]float64
+
+	// UsageSource overrides the top-level UsageSource for this pool. When nil, the pool uses
+	// the top-level NodeResourceUtilizationThresholds.UsageSource.
+	UsageSource *UsageSource
+}
+
+// UsageSourceType selects which backend a UsageSource queries for resource usage.
+type UsageSourceType string
+
+const (
+	// RequestsUsageSourceType derives usage from summed pod resource requests.
+	RequestsUsageSourceType UsageSourceType = "Requests"
+	// MetricsServerUsageSourceType derives usage from the metrics.k8s.io NodeMetrics/PodMetrics API.
+	MetricsServerUsageSourceType UsageSourceType = "MetricsServer"
+	// PrometheusUsageSourceType derives usage by querying a Prometheus server.
+	PrometheusUsageSourceType UsageSourceType = "Prometheus"
+)
+
+// UsageSource configures how LowNodeUtilization measures real node/pod resource usage instead
+// of relying solely on resource requests.
+type UsageSource struct {
+	Type UsageSourceType
+
+	// Window is substituted for {{.Window}} in PrometheusQueries, e.g. "5m" or "15m". Defaults
+	// to "5m". Only the Prometheus source reads it; the MetricsServer and Requests sources
+	// ignore it.
+	Window string
+
+	// UsageAvoidanceThreshold ignores usage deltas no larger than this percentage so that a
+	// transient spike doesn't, by itself, change a node's classification.
+	UsageAvoidanceThreshold Percentage
+
+	// PrometheusAddress is the base URL of the Prometheus server to query. Only used when
+	// Type is PrometheusUsageSourceType.
+	PrometheusAddress string
+
+	// PrometheusQueries holds a PromQL query template per resource, only used when Type is
+	// PrometheusUsageSourceType. Templates may reference {{.NodeName}} and {{.Window}}.
+	PrometheusQueries map[v1.ResourceName]string
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nodefit answers a narrower question than full scheduling: given a pod being
+// considered for eviction and a set of candidate nodes, would the scheduler place the pod on
+// any of them? Strategies like LowNodeUtilization use this to avoid evicting pods that would
+// just bounce back onto the node they came from because nothing else can host them.
+package nodefit
+
+import (
+	"k8s.io/kubernetes/pkg/api/v1"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+// Candidate pairs a node with the pods already scheduled onto it, so Fits can account for
+// resources the node has already committed when checking whether another pod would fit.
+type Candidate struct {
+	Node *v1.Node
+	Pods []*v1.Pod
+}
+
+// Fits reports whether pod could be scheduled onto at least one of candidates. It runs the
+// subset of the scheduler's predicates that matter for this decision - nodeSelector/affinity,
+// taints/tolerations, the node's Unschedulable flag, and basic resource fit - rather than the
+// full predicate list, since LowNodeUtilization only needs to know whether the pod could land
+// somewhere else.
+func Fits(pod *v1.Pod, candidates []Candidate) bool {
+	for _, candidate := range candidates {
+		if fitsNode(pod, candidate.Node, candidate.Pods) {
+			return true
+		}
+	}
+	return false
+}
+
+func fitsNode(pod *v1.Pod, node *v1.Node, existingPods []*v1.Pod) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+
+	nodeInfo := schedulercache.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	for _, existing := range existingPods {
+		nodeInfo.AddPod(existing)
+	}
+
+	if ok, err := predicates.PodMatchNodeSelector(pod, nodeInfo); err != nil || !ok {
+		return false
+	}
+	if ok, err := predicates.PodToleratesNodeTaints(pod, nodeInfo); err != nil || !ok {
+		return false
+	}
+	if ok, _, err := predicates.PodFitsResources(pod, nodeInfo); err != nil || !ok {
+		return false
+	}
+	return true
+}
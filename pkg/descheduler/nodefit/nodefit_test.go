@@ -0,0 +1,82 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefit
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/api/v1"
+)
+
+func nodeWithCPU(name string, milliCPU int64) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:  *resource.NewMilliQuantity(milliCPU, resource.DecimalSI),
+				v1.ResourcePods: *resource.NewQuantity(110, resource.DecimalSI),
+			},
+		},
+	}
+}
+
+func podRequestingCPU(name string, milliCPU int64) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU: *resource.NewMilliQuantity(milliCPU, resource.DecimalSI),
+					},
+				},
+			}},
+		},
+	}
+}
+
+func TestFitsAccountsForExistingPods(t *testing.T) {
+	node := nodeWithCPU("low-node", 1000)
+	existing := podRequestingCPU("already-scheduled", 900)
+	candidate := podRequestingCPU("evicted", 500)
+
+	if Fits(candidate, []Candidate{{Node: node, Pods: []*v1.Pod{existing}}}) {
+		t.Fatalf("expected pod not to fit: node only has 100m free but pod requests 500m")
+	}
+
+	if !Fits(candidate, []Candidate{{Node: node, Pods: nil}}) {
+		t.Fatalf("expected pod to fit an empty node with 1000m allocatable")
+	}
+}
+
+func TestFitsSkipsUnschedulableNode(t *testing.T) {
+	node := nodeWithCPU("cordoned", 4000)
+	node.Spec.Unschedulable = true
+	candidate := podRequestingCPU("pod", 100)
+
+	if Fits(candidate, []Candidate{{Node: node}}) {
+		t.Fatalf("expected pod not to fit an unschedulable node")
+	}
+}
+
+func TestFitsReturnsFalseWithNoCandidates(t *testing.T) {
+	if Fits(podRequestingCPU("pod", 100), nil) {
+		t.Fatalf("expected no candidates to mean no fit")
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podselection
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/api/v1"
+)
+
+func podWithPriorityAndAge(name string, priority int32, age time.Duration) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+		Spec: v1.PodSpec{Priority: &priority},
+	}
+}
+
+func TestCompositeSorterWeighsPriorityWithDefaultWeights(t *testing.T) {
+	// Both pods are about the same age, so with equal default weights the lower-priority pod
+	// must still sort first; if priority were swamped by raw epoch seconds (as it was before
+	// age was normalized to a relative duration) this would degenerate to creation order only.
+	low := podWithPriorityAndAge("low-priority", 10, time.Minute)
+	high := podWithPriorityAndAge("high-priority", 1000, time.Minute)
+
+	sorted := CompositeSorter{Weights: Weights{Priority: 1, Age: 1}}.Sort([]*v1.Pod{high, low})
+	if sorted[0].Name != "low-priority" {
+		t.Fatalf("expected low-priority pod first, got %q", sorted[0].Name)
+	}
+}
+
+func TestCompositeSorterWeighsAgeWithDefaultWeights(t *testing.T) {
+	// Equal priority, different ages: the younger pod should sort first.
+	old := podWithPriorityAndAge("old", 100, 24*time.Hour)
+	young := podWithPriorityAndAge("young", 100, time.Minute)
+
+	sorted := CompositeSorter{Weights: Weights{Priority: 1, Age: 1}}.Sort([]*v1.Pod{old, young})
+	if sorted[0].Name != "young" {
+		t.Fatalf("expected young pod first, got %q", sorted[0].Name)
+	}
+}
+
+func TestPriorityOnlySorterIgnoresQoS(t *testing.T) {
+	a := podWithPriorityAndAge("a", 5, time.Minute)
+	b := podWithPriorityAndAge("b", 1, time.Minute)
+
+	sorted := PriorityOnlySorter{}.Sort([]*v1.Pod{a, b})
+	if sorted[0].Name != "b" || sorted[1].Name != "a" {
+		t.Fatalf("expected ascending priority order [b, a], got [%s, %s]", sorted[0].Name, sorted[1].Name)
+	}
+}
+
+func TestYoungestFirstSorterOrdersByDescendingCreationTimestamp(t *testing.T) {
+	old := podWithPriorityAndAge("old", 0, 24*time.Hour)
+	young := podWithPriorityAndAge("young", 0, time.Minute)
+
+	sorted := YoungestFirstSorter{}.Sort([]*v1.Pod{old, young})
+	if sorted[0].Name != "young" {
+		t.Fatalf("expected young pod first, got %q", sorted[0].Name)
+	}
+}
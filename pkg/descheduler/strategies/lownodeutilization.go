@@ -20,7 +20,8 @@ import (
 	"sort"
 
 	"github.com/golang/glog"
-	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/kubernetes/pkg/api/v1"
 	helper "k8s.io/kubernetes/pkg/api/v1/resource"
 	"k8s.io/kubernetes/pkg/client/clientset_generated/clientset"
@@ -28,7 +29,10 @@ import (
 	"github.com/kubernetes-incubator/descheduler/cmd/descheduler/app/options"
 	"github.com/kubernetes-incubator/descheduler/pkg/api"
 	"github.com/kubernetes-incubator/descheduler/pkg/descheduler/evictions"
+	"github.com/kubernetes-incubator/descheduler/pkg/descheduler/nodefit"
 	podutil "github.com/kubernetes-incubator/descheduler/pkg/descheduler/pod"
+	"github.com/kubernetes-incubator/descheduler/pkg/descheduler/podselection"
+	"github.com/kubernetes-incubator/descheduler/pkg/descheduler/usage"
 )
 
 type NodeUsageMap struct {
@@ -45,54 +49,138 @@ func LowNodeUtilization(ds *options.DeschedulerServer, strategy api.DeschedulerS
 	if !strategy.Enabled {
 		return
 	}
+
+	params := strategy.Params.NodeResourceUtilizationThresholds
+	simulateScheduling := true
+	if params.SimulateScheduling != nil {
+		simulateScheduling = *params.SimulateScheduling
+	}
+
+	totalEvicted := 0
+	remaining := nodes
+
+	if len(params.NodePools) > 0 {
+		assignments, unassigned := assignNodesToPools(nodes, params.NodePools)
+		for _, assignment := range assignments {
+			glog.V(1).Infof("rebalancing node pool %q (%d nodes)", assignment.pool.Name, len(assignment.nodes))
+			usageSourceSpec := assignment.pool.UsageSource
+			if usageSourceSpec == nil {
+				usageSourceSpec = params.UsageSource
+			}
+			totalEvicted += evictForNodes(ds, evictionPolicyGroupVersion, assignment.nodes, assignment.pool.Thresholds, assignment.pool.TargetThresholds, assignment.pool.NumberOfNodes, assignment.pool.ResourceWeights, usageSourceSpec, simulateScheduling, params.NodeFit, params.PodEvictionOrder)
+		}
+		remaining = unassigned
+	}
+
+	if len(remaining) > 0 {
+		totalEvicted += evictForNodes(ds, evictionPolicyGroupVersion, remaining, params.Thresholds, params.TargetThresholds, params.NumberOfNodes, params.ResourceWeights, params.UsageSource, simulateScheduling, params.NodeFit, params.PodEvictionOrder)
+	}
+	glog.V(1).Infof("LowNodeUtilization evicted %d pods total", totalEvicted)
+}
+
+// poolAssignment pairs a NodePoolSpec with the nodes from the input set that matched its
+// Selector.
+type poolAssignment struct {
+	pool  api.NodePoolSpec
+	nodes []*v1.Node
+}
+
+// assignNodesToPools partitions nodes across pools by each pool's label Selector, in order; a
+// node already claimed by an earlier pool cannot also be claimed by a later one, so overlapping
+// selectors resolve to "first pool listed wins" rather than double-counting the node. Nodes
+// matching no pool are returned as the second value, for LowNodeUtilization's top-level
+// threshold fallback.
+func assignNodesToPools(nodes []*v1.Node, pools []api.NodePoolSpec) ([]poolAssignment, []*v1.Node) {
+	assigned := map[*v1.Node]bool{}
+	assignments := make([]poolAssignment, 0, len(pools))
+	for _, pool := range pools {
+		selector, err := metav1.LabelSelectorAsSelector(&pool.Selector)
+		if err != nil {
+			glog.Errorf("node pool %q has an invalid selector, skipping: %v", pool.Name, err)
+			continue
+		}
+		var poolNodes []*v1.Node
+		for _, node := range nodes {
+			if !assigned[node] && selector.Matches(labels.Set(node.Labels)) {
+				poolNodes = append(poolNodes, node)
+				assigned[node] = true
+			}
+		}
+		if len(poolNodes) == 0 {
+			continue
+		}
+		assignments = append(assignments, poolAssignment{pool: pool, nodes: poolNodes})
+	}
+
+	var unassigned []*v1.Node
+	for _, node := range nodes {
+		if !assigned[node] {
+			unassigned = append(unassigned, node)
+		}
+	}
+	return assignments, unassigned
+}
+
+// evictForNodes runs one classify-and-evict pass of LowNodeUtilization over nodes, using the
+// given thresholds/weights/usage source. It's the unit of work both the top-level strategy
+// params and each NodePoolSpec reduce to.
+func evictForNodes(
+	ds *options.DeschedulerServer,
+	evictionPolicyGroupVersion string,
+	nodes []*v1.Node,
+	thresholds, targetThresholds api.ResourceThresholds,
+	numberOfNodes int,
+	resourceWeights map[v1.ResourceName]float64,
+	usageSourceSpec *api.UsageSource,
+	simulateScheduling, nodeFit bool,
+	podEvictionOrder *api.PodEvictionOrder,
+) int {
 	// todo: move to config validation?
 	// TODO: May be create a struct for the strategy as well, so that we don't have to pass along the all the params?
 
-	thresholds := strategy.Params.NodeResourceUtilizationThresholds.Thresholds
 	if !validateThresholds(thresholds) {
-		return
+		return 0
 	}
-	targetThresholds := strategy.Params.NodeResourceUtilizationThresholds.TargetThresholds
 	if !validateTargetThresholds(targetThresholds) {
-		return
+		return 0
+	}
+	resourceNames := resourceNamesFrom(thresholds, targetThresholds)
+
+	usageSource, err := usage.NewSource(usageSourceSpec, ds.MetricsClient)
+	if err != nil {
+		glog.Errorf("unable to build usage source: %v", err)
+		return 0
+	}
+	var usageAvoidanceThreshold api.Percentage
+	if usageSourceSpec != nil {
+		usageAvoidanceThreshold = usageSourceSpec.UsageAvoidanceThreshold
 	}
 
 	npm := CreateNodePodsMap(ds.Client, nodes)
-	lowNodes, targetNodes, _ := classifyNodes(npm, thresholds, targetThresholds)
+	lowNodes, targetNodes, _ := classifyNodes(npm, thresholds, targetThresholds, usageSource, resourceNames, usageAvoidanceThreshold)
 
 	if len(lowNodes) == 0 {
 		glog.V(1).Infof("No node is underutilized")
-		return
-	} else if len(lowNodes) < strategy.Params.NodeResourceUtilizationThresholds.NumberOfNodes {
+		return 0
+	} else if len(lowNodes) < numberOfNodes {
 		glog.V(1).Infof("number of nodes underutilized is less than NumberOfNodes")
-		return
+		return 0
 	} else if len(lowNodes) == len(nodes) {
 		glog.V(1).Infof("all nodes are underutilized")
-		return
+		return 0
 	} else if len(targetNodes) == 0 {
 		glog.V(1).Infof("no node is above target utilization")
-		return
+		return 0
 	}
-	evictPodsFromTargetNodes(ds.Client, evictionPolicyGroupVersion, targetNodes, lowNodes, targetThresholds, ds.DryRun)
+	return evictPodsFromTargetNodes(ds.Client, evictionPolicyGroupVersion, targetNodes, lowNodes, thresholds, targetThresholds, resourceNames, resourceWeights, usageSource, simulateScheduling, nodeFit, podEvictionOrder, ds.DryRun)
 }
 
 func validateThresholds(thresholds api.ResourceThresholds) bool {
-	if thresholds == nil {
+	if len(thresholds) == 0 {
 		glog.V(1).Infof("no resource threshold is configured")
 		return false
 	}
-	found := false
-	for name := range thresholds {
-		if name == v1.ResourceCPU || name == v1.ResourceMemory || name == v1.ResourcePods {
-			found = true
-			break
-		}
-	}
-	if !found {
-		glog.V(1).Infof("one of cpu, memory, or pods resource threshold must be configured")
-		return false
-	}
-	return found
+	return true
 }
 
 //This function could be merged into above once we are clear.
@@ -107,16 +195,37 @@ func validateTargetThresholds(targetThresholds api.ResourceThresholds) bool {
 	return true
 }
 
-func classifyNodes(npm NodePodsMap, thresholds api.ResourceThresholds, targetThresholds api.ResourceThresholds) ([]NodeUsageMap, []NodeUsageMap, []NodeUsageMap) {
+// resourceNamesFrom returns the set of resource names configured across thresholds and
+// targetThresholds, e.g. cpu/memory/pods plus any operator-configured ephemeral-storage or
+// extended resource (nvidia.com/gpu, kubernetes.io/batch-cpu, ...).
+func resourceNamesFrom(thresholds, targetThresholds api.ResourceThresholds) []v1.ResourceName {
+	seen := map[v1.ResourceName]bool{}
+	names := []v1.ResourceName{}
+	for name := range thresholds {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range targetThresholds {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func classifyNodes(npm NodePodsMap, thresholds api.ResourceThresholds, targetThresholds api.ResourceThresholds, usageSource usage.Source, resourceNames []v1.ResourceName, usageAvoidanceThreshold api.Percentage) ([]NodeUsageMap, []NodeUsageMap, []NodeUsageMap) {
 	lowNodes, targetNodes, otherNodes := []NodeUsageMap{}, []NodeUsageMap{}, []NodeUsageMap{}
 	for node, pods := range npm {
-		usage, nonRemovablePods, bePods, bPods, gPods := NodeUtilization(node, pods)
-		nuMap := NodeUsageMap{node, usage, nonRemovablePods, bePods, bPods, gPods}
-		glog.V(1).Infof("Node %#v usage: %#v", node.Name, usage)
+		nodeUsage, nonRemovablePods, bePods, bPods, gPods := NodeUtilization(node, pods, usageSource, resourceNames, usageAvoidanceThreshold)
+		nuMap := NodeUsageMap{node, nodeUsage, nonRemovablePods, bePods, bPods, gPods}
+		glog.V(1).Infof("Node %#v usage: %#v", node.Name, nodeUsage)
 
-		if IsNodeWithLowUtilization(usage, thresholds) {
+		if IsNodeWithLowUtilization(nodeUsage, thresholds) {
 			lowNodes = append(lowNodes, nuMap)
-		} else if IsNodeAboveTargetUtilization(usage, targetThresholds) {
+		} else if IsNodeAboveTargetUtilization(nodeUsage, targetThresholds) {
 			targetNodes = append(targetNodes, nuMap)
 		} else {
 			// Seems we don't need to collect them?
@@ -126,32 +235,50 @@ func classifyNodes(npm NodePodsMap, thresholds api.ResourceThresholds, targetThr
 	return lowNodes, targetNodes, otherNodes
 }
 
-func evictPodsFromTargetNodes(client clientset.Interface, evictionPolicyGroupVersion string, targetNodes, lowNodes []NodeUsageMap, targetThresholds api.ResourceThresholds, dryRun bool) int {
+func evictPodsFromTargetNodes(
+	client clientset.Interface,
+	evictionPolicyGroupVersion string,
+	targetNodes, lowNodes []NodeUsageMap,
+	thresholds, targetThresholds api.ResourceThresholds,
+	resourceNames []v1.ResourceName,
+	resourceWeights map[v1.ResourceName]float64,
+	usageSource usage.Source,
+	simulateScheduling, nodeFit bool,
+	podEvictionOrder *api.PodEvictionOrder,
+	dryRun bool,
+) int {
 	podsEvicted := 0
 
-	SortNodesByUsage(targetNodes)
+	SortNodesByUsage(targetNodes, resourceWeights)
+
+	lowNodeList := make([]nodefit.Candidate, 0, len(lowNodes))
+	for _, node := range lowNodes {
+		pods := make([]*v1.Pod, 0, len(node.nonRemovablePods)+len(node.bePods)+len(node.bPods)+len(node.gPods))
+		pods = append(pods, node.nonRemovablePods...)
+		pods = append(pods, node.bePods...)
+		pods = append(pods, node.bPods...)
+		pods = append(pods, node.gPods...)
+		lowNodeList = append(lowNodeList, nodefit.Candidate{Node: node.node, Pods: pods})
+	}
 
-	// upper bound on total number of pods/cpu/memory to be moved
-	var totalPods, totalCpu, totalMem float64
+	// upper bound on the total amount of each configured resource to be moved
+	totalAvailable := map[v1.ResourceName]float64{}
 	for _, node := range lowNodes {
 		nodeCapacity := node.node.Status.Capacity
 		if len(node.node.Status.Allocatable) > 0 {
 			nodeCapacity = node.node.Status.Allocatable
 		}
-		// totalPods to be moved
-		podsPercentage := targetThresholds[v1.ResourcePods] - node.usage[v1.ResourcePods]
-		totalPods += ((float64(podsPercentage) * float64(nodeCapacity.Pods().Value())) / 100)
-
-		// totalCPU capacity to be moved
-		if _, ok := targetThresholds[v1.ResourceCPU]; ok {
-			cpuPercentage := targetThresholds[v1.ResourceCPU] - node.usage[v1.ResourceCPU]
-			totalCpu += ((float64(cpuPercentage) * float64(nodeCapacity.Cpu().MilliValue())) / 100)
-		}
-
-		// totalMem capacity to be moved
-		if _, ok := targetThresholds[v1.ResourceMemory]; ok {
-			memPercentage := targetThresholds[v1.ResourceMemory] - node.usage[v1.ResourceMemory]
-			totalMem += ((float64(memPercentage) * float64(nodeCapacity.Memory().Value())) / 100)
+		for _, name := range resourceNames {
+			targetValue, ok := targetThresholds[name]
+			if !ok {
+				continue
+			}
+			capValue, ok := resourceCapacityValue(nodeCapacity, name)
+			if !ok {
+				continue
+			}
+			percentage := targetValue - node.usage[name]
+			totalAvailable[name] += float64(percentage) * capValue / 100
 		}
 	}
 
@@ -161,32 +288,88 @@ func evictPodsFromTargetNodes(client clientset.Interface, evictionPolicyGroupVer
 			nodeCapacity = node.node.Status.Allocatable
 		}
 		glog.V(1).Infof("evicting pods from node %#v with usage: %#v", node.node.Name, node.usage)
-		// evict best effort pods
-		evictPods(node.bePods, client, evictionPolicyGroupVersion, targetThresholds, nodeCapacity, node.usage, &totalPods, &totalCpu, &totalMem, &podsEvicted, dryRun)
-		// evict burstable pods
-		evictPods(node.bPods, client, evictionPolicyGroupVersion, targetThresholds, nodeCapacity, node.usage, &totalPods, &totalCpu, &totalMem, &podsEvicted, dryRun)
-		// evict guaranteed pods
-		evictPods(node.gPods, client, evictionPolicyGroupVersion, targetThresholds, nodeCapacity, node.usage, &totalPods, &totalCpu, &totalMem, &podsEvicted, dryRun)
+		candidates := orderedEvictionCandidates(node, podEvictionOrder)
+		evictPods(candidates, client, evictionPolicyGroupVersion, thresholds, targetThresholds, resourceNames, nodeCapacity, node.usage, usageSource, lowNodes, lowNodeList, simulateScheduling, nodeFit, totalAvailable, &podsEvicted, dryRun)
 	}
 	return podsEvicted
 }
 
-func evictPods(inputPods []*v1.Pod,
+// orderedEvictionCandidates returns node's removable pods (its BestEffort, Burstable, and
+// Guaranteed pods) ordered according to podEvictionOrder. A nil podEvictionOrder, or an unknown
+// Type, falls back to QoSThenPriority: LowNodeUtilization's historical QoS class order, with
+// Burstable and Guaranteed pods each sorted by ascending priority.
+func orderedEvictionCandidates(node NodeUsageMap, podEvictionOrder *api.PodEvictionOrder) []*v1.Pod {
+	qosThenPriority := podselection.QoSThenPrioritySorter{BePods: node.bePods, BPods: node.bPods, GPods: node.gPods}
+	if podEvictionOrder == nil {
+		return qosThenPriority.Sort(nil)
+	}
+
+	all := append([]*v1.Pod{}, node.bePods...)
+	all = append(all, node.bPods...)
+	all = append(all, node.gPods...)
+
+	var sorter podselection.Sorter
+	switch podEvictionOrder.Type {
+	case "", api.QoSThenPriorityOrder:
+		sorter = qosThenPriority
+	case api.PriorityOnlyOrder:
+		sorter = podselection.PriorityOnlySorter{}
+	case api.YoungestFirstOrder:
+		sorter = podselection.YoungestFirstSorter{}
+	case api.CompositeOrder:
+		weights := podselection.Weights{Priority: 1, Age: 1}
+		if podEvictionOrder.CompositeWeights != nil {
+			weights = podselection.Weights{Priority: podEvictionOrder.CompositeWeights.Priority, Age: podEvictionOrder.CompositeWeights.Age}
+		}
+		sorter = podselection.CompositeSorter{Weights: weights}
+	default:
+		glog.Errorf("unknown pod eviction order %q, falling back to QoSThenPriority", podEvictionOrder.Type)
+		sorter = qosThenPriority
+	}
+	return sorter.Sort(all)
+}
+
+func evictPods(
+	inputPods []*v1.Pod,
 	client clientset.Interface,
 	evictionPolicyGroupVersion string,
+	thresholds api.ResourceThresholds,
 	targetThresholds api.ResourceThresholds,
+	resourceNames []v1.ResourceName,
 	nodeCapacity v1.ResourceList,
 	nodeUsage api.ResourceThresholds,
-	totalPods *float64,
-	totalCpu *float64,
-	totalMem *float64,
+	usageSource usage.Source,
+	lowNodes []NodeUsageMap,
+	lowNodeList []nodefit.Candidate,
+	simulateScheduling bool,
+	nodeFit bool,
+	totalAvailable map[v1.ResourceName]float64,
 	podsEvicted *int,
-	dryRun bool) {
-	if IsNodeAboveTargetUtilization(nodeUsage, targetThresholds) && (*totalPods > 0 || *totalCpu > 0 || *totalMem > 0) {
+	dryRun bool,
+) {
+	if IsNodeAboveTargetUtilization(nodeUsage, targetThresholds) && anyResourceAvailable(totalAvailable) {
 		onePodPercentage := api.Percentage((float64(1) * 100) / float64(nodeCapacity.Pods().Value()))
 		for _, pod := range inputPods {
-			cUsage := helper.GetResourceRequest(pod, v1.ResourceCPU)
-			mUsage := helper.GetResourceRequest(pod, v1.ResourceMemory)
+			podUsage, err := podResourceUsages(pod, resourceNames, usageSource)
+			if err != nil {
+				glog.Infof("unable to determine usage of pod %#v, skipping: %v", pod.Name, err)
+				continue
+			}
+
+			if nodeFit && !nodefit.Fits(pod, lowNodeList) {
+				glog.V(1).Infof("skipping eviction of pod %#v: no low node would accept it (nodeSelector, affinity, taints, or resources don't match)", pod.Name)
+				continue
+			}
+
+			var landingNode *NodeUsageMap
+			if simulateScheduling {
+				landingNode = bestFitLowNode(lowNodes, thresholds, resourceNames, podUsage)
+				if landingNode == nil {
+					glog.V(1).Infof("skipping eviction of pod %#v: no low node can absorb it without crossing thresholds", pod.Name)
+					continue
+				}
+			}
+
 			success, err := evictions.EvictPod(client, pod, evictionPolicyGroupVersion, dryRun)
 			if !success {
 				glog.Infof("Error when evicting pod: %#v (%#v)", pod.Name, err)
@@ -195,19 +378,30 @@ func evictPods(inputPods []*v1.Pod,
 				// update remaining pods
 				*podsEvicted++
 				nodeUsage[v1.ResourcePods] -= onePodPercentage
-				*totalPods--
+				totalAvailable[v1.ResourcePods]--
+
+				// update remaining usage for every configured resource
+				for _, name := range resourceNames {
+					if name == v1.ResourcePods {
+						continue
+					}
+					capValue, ok := resourceCapacityValue(nodeCapacity, name)
+					if !ok {
+						continue
+					}
+					used := float64(podUsage[name])
+					totalAvailable[name] -= used
+					nodeUsage[name] -= api.Percentage(used * 100 / capValue)
+				}
 
-				// update remaining cpu
-				*totalCpu -= float64(cUsage)
-				nodeUsage[v1.ResourceCPU] -= api.Percentage((float64(cUsage) * 100) / float64(nodeCapacity.Cpu().MilliValue()))
+				glog.V(1).Infof("updated node usage: %#v", nodeUsage)
 
-				// update remaining memory
-				*totalMem -= float64(mUsage)
-				nodeUsage[v1.ResourceMemory] -= api.Percentage(float64(mUsage) / float64(nodeCapacity.Memory().Value()) * 100)
+				if landingNode != nil {
+					projectPodOntoNode(landingNode, resourceNames, podUsage)
+				}
 
-				glog.V(1).Infof("updated node usage: %#v", nodeUsage)
-				// check if node utilization drops below target threshold or required capacity (cpu, memory, pods) is moved
-				if !IsNodeAboveTargetUtilization(nodeUsage, targetThresholds) || (*totalPods <= 0 && *totalCpu <= 0 && *totalMem <= 0) {
+				// check if node utilization drops below target threshold or required capacity is moved
+				if !IsNodeAboveTargetUtilization(nodeUsage, targetThresholds) || !anyResourceAvailable(totalAvailable) {
 					break
 				}
 			}
@@ -215,24 +409,176 @@ func evictPods(inputPods []*v1.Pod,
 	}
 }
 
-func SortNodesByUsage(nodes []NodeUsageMap) {
-	sort.Slice(nodes, func(i, j int) bool {
-		var ti, tj api.Percentage
-		for name, value := range nodes[i].usage {
-			if name == v1.ResourceCPU || name == v1.ResourceMemory || name == v1.ResourcePods {
-				ti += value
+func anyResourceAvailable(totalAvailable map[v1.ResourceName]float64) bool {
+	for _, value := range totalAvailable {
+		if value > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceCapacityValue returns name's capacity as a float64 in the unit NodeUtilization and
+// evictPods use for percentage math: milli-units for cpu, whole units for everything else.
+func resourceCapacityValue(nodeCapacity v1.ResourceList, name v1.ResourceName) (float64, bool) {
+	quantity, ok := nodeCapacity[name]
+	if !ok {
+		return 0, false
+	}
+	if name == v1.ResourceCPU {
+		return float64(quantity.MilliValue()), true
+	}
+	return float64(quantity.Value()), true
+}
+
+// podResourceUsages returns pod's usage for every resource in resourceNames. CPU and memory come
+// from the configured usage.Source (so they can reflect real load); every other resource
+// (ephemeral-storage, extended resources like nvidia.com/gpu) comes from the pod's requests,
+// since no usage source here observes load for those. If usageSource can't report per-pod usage
+// (PrometheusSource, which only exposes node-level queries), cpu/memory fall back to
+// RequestsSource too, rather than failing every candidate pod's eviction.
+func podResourceUsages(pod *v1.Pod, resourceNames []v1.ResourceName, usageSource usage.Source) (map[v1.ResourceName]int64, error) {
+	usages := map[v1.ResourceName]int64{v1.ResourcePods: 1}
+
+	needsRequests := false
+	for _, name := range resourceNames {
+		if name != v1.ResourceCPU && name != v1.ResourceMemory && name != v1.ResourcePods {
+			needsRequests = true
+			break
+		}
+	}
+
+	for _, name := range resourceNames {
+		if name == v1.ResourceCPU || name == v1.ResourceMemory {
+			cpuMilli, memBytes, err := usageSource.PodUsage(pod)
+			if err != nil {
+				glog.V(1).Infof("usage source does not support per-pod usage for pod %#v, falling back to requests: %v", pod.Name, err)
+				cpuMilli, memBytes, err = (usage.RequestsSource{}).PodUsage(pod)
+				if err != nil {
+					return nil, err
+				}
 			}
+			usages[v1.ResourceCPU] = cpuMilli
+			usages[v1.ResourceMemory] = memBytes
+			break
 		}
-		for name, value := range nodes[j].usage {
+	}
+
+	if needsRequests {
+		req, _, err := helper.PodRequestsAndLimits(pod)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range resourceNames {
 			if name == v1.ResourceCPU || name == v1.ResourceMemory || name == v1.ResourcePods {
-				tj += value
+				continue
+			}
+			if quantity, ok := req[name]; ok {
+				usages[name] = quantity.Value()
+			}
+		}
+	}
+	return usages, nil
+}
+
+// bestFitLowNode simulates placing a pod (using its per-resource usage as reported by
+// podResourceUsages) onto each low node and returns the one with the largest remaining headroom,
+// summed across every resource in resourceNames, after the placement, without crossing
+// thresholds. It returns nil if no low node could absorb the pod.
+func bestFitLowNode(lowNodes []NodeUsageMap, thresholds api.ResourceThresholds, resourceNames []v1.ResourceName, podUsage map[v1.ResourceName]int64) *NodeUsageMap {
+	var best *NodeUsageMap
+	var bestHeadroom api.Percentage
+
+	for i := range lowNodes {
+		node := &lowNodes[i]
+		nodeCapacity := node.node.Status.Capacity
+		if len(node.node.Status.Allocatable) > 0 {
+			nodeCapacity = node.node.Status.Allocatable
+		}
+
+		fits := true
+		var headroom api.Percentage
+		for _, name := range resourceNames {
+			capValue, ok := resourceCapacityValue(nodeCapacity, name)
+			if !ok {
+				// The node has none of this resource at all (e.g. a non-GPU node being
+				// considered for a pod needing nvidia.com/gpu). That's only a problem if the
+				// pod actually needs some of it.
+				if podResourceUsageValue(name, podUsage) > 0 {
+					fits = false
+					break
+				}
+				continue
+			}
+			projected := node.usage[name] + api.Percentage(podResourceUsageValue(name, podUsage)*100/capValue)
+
+			value, ok := thresholds[name]
+			if !ok {
+				continue
+			}
+			if projected > value {
+				fits = false
+				break
 			}
+			headroom += value - projected
+		}
+		if !fits {
+			continue
+		}
+		if best == nil || headroom > bestHeadroom {
+			best = node
+			bestHeadroom = headroom
+		}
+	}
+	return best
+}
+
+// projectPodOntoNode records a simulated pod placement on a low node, across every resource in
+// resourceNames, so that subsequent bestFitLowNode calls see its reduced headroom.
+func projectPodOntoNode(node *NodeUsageMap, resourceNames []v1.ResourceName, podUsage map[v1.ResourceName]int64) {
+	nodeCapacity := node.node.Status.Capacity
+	if len(node.node.Status.Allocatable) > 0 {
+		nodeCapacity = node.node.Status.Allocatable
+	}
+	for _, name := range resourceNames {
+		capValue, ok := resourceCapacityValue(nodeCapacity, name)
+		if !ok {
+			continue
 		}
-		// To return sorted in descending order
-		return ti > tj
+		node.usage[name] += api.Percentage(podResourceUsageValue(name, podUsage) * 100 / capValue)
+	}
+}
+
+// podResourceUsageValue returns podUsage's value for name as a float64, treating the implicit
+// "one pod" usage of v1.ResourcePods the same way podResourceUsages itself does.
+func podResourceUsageValue(name v1.ResourceName, podUsage map[v1.ResourceName]int64) float64 {
+	if name == v1.ResourcePods {
+		return 1
+	}
+	return float64(podUsage[name])
+}
+
+// SortNodesByUsage sorts nodes in descending order by a weighted composite of their resource
+// usage percentages, so the most-utilized nodes are evicted from first. A resource with no entry
+// in resourceWeights defaults to a weight of 1.
+func SortNodesByUsage(nodes []NodeUsageMap, resourceWeights map[v1.ResourceName]float64) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return weightedUsage(nodes[i].usage, resourceWeights) > weightedUsage(nodes[j].usage, resourceWeights)
 	})
 }
 
+func weightedUsage(usage api.ResourceThresholds, resourceWeights map[v1.ResourceName]float64) float64 {
+	var total float64
+	for name, value := range usage {
+		weight, ok := resourceWeights[name]
+		if !ok {
+			weight = 1
+		}
+		total += float64(value) * weight
+	}
+	return total
+}
+
 func CreateNodePodsMap(client clientset.Interface, nodes []*v1.Node) NodePodsMap {
 	npm := NodePodsMap{}
 	for _, node := range nodes {
@@ -248,12 +594,10 @@ func CreateNodePodsMap(client clientset.Interface, nodes []*v1.Node) NodePodsMap
 
 func IsNodeAboveTargetUtilization(nodeThresholds api.ResourceThresholds, thresholds api.ResourceThresholds) bool {
 	for name, nodeValue := range nodeThresholds {
-		if name == v1.ResourceCPU || name == v1.ResourceMemory || name == v1.ResourcePods {
-			if value, ok := thresholds[name]; !ok {
-				continue
-			} else if nodeValue > value {
-				return true
-			}
+		if value, ok := thresholds[name]; !ok {
+			continue
+		} else if nodeValue > value {
+			return true
 		}
 	}
 	return false
@@ -261,23 +605,43 @@ func IsNodeAboveTargetUtilization(nodeThresholds api.ResourceThresholds, thresho
 
 func IsNodeWithLowUtilization(nodeThresholds api.ResourceThresholds, thresholds api.ResourceThresholds) bool {
 	for name, nodeValue := range nodeThresholds {
-		if name == v1.ResourceCPU || name == v1.ResourceMemory || name == v1.ResourcePods {
-			if value, ok := thresholds[name]; !ok {
-				continue
-			} else if nodeValue > value {
-				return false
-			}
+		if value, ok := thresholds[name]; !ok {
+			continue
+		} else if nodeValue > value {
+			return false
 		}
 	}
 	return true
 }
 
-func NodeUtilization(node *v1.Node, pods []*v1.Pod) (api.ResourceThresholds, []*v1.Pod, []*v1.Pod, []*v1.Pod, []*v1.Pod) {
+// dampUsageSpike returns baseline in place of rawUsage when the two are within
+// usageAvoidanceThreshold percentage points of each other, so a transient spike in observed
+// load doesn't by itself flip a node's classification. A zero threshold disables damping.
+func dampUsageSpike(rawUsage, baseline, usageAvoidanceThreshold api.Percentage) api.Percentage {
+	if usageAvoidanceThreshold <= 0 {
+		return rawUsage
+	}
+	delta := rawUsage - baseline
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta <= usageAvoidanceThreshold {
+		return baseline
+	}
+	return rawUsage
+}
+
+// NodeUtilization computes node's usage percentage for every resource in resourceNames. cpu and
+// memory usage come from usageSource, damped against the RequestsSource baseline by
+// usageAvoidanceThreshold so a transient spike doesn't by itself change a node's classification;
+// pods is simply the scheduled pod count; every other resource (ephemeral-storage, extended
+// resources) is summed from PodRequestsAndLimits, since usage.Source only observes real load for
+// cpu/memory.
+func NodeUtilization(node *v1.Node, pods []*v1.Pod, usageSource usage.Source, resourceNames []v1.ResourceName, usageAvoidanceThreshold api.Percentage) (api.ResourceThresholds, []*v1.Pod, []*v1.Pod, []*v1.Pod, []*v1.Pod) {
 	bePods := []*v1.Pod{}
 	nonRemovablePods := []*v1.Pod{}
 	bPods := []*v1.Pod{}
 	gPods := []*v1.Pod{}
-	totalReqs := map[v1.ResourceName]resource.Quantity{}
 	for _, pod := range pods {
 		sr, err := podutil.CreatorRef(pod)
 		if err != nil {
@@ -297,35 +661,63 @@ func NodeUtilization(node *v1.Node, pods []*v1.Pod) (api.ResourceThresholds, []*
 		} else {
 			gPods = append(gPods, pod)
 		}
+	}
 
-		req, _, err := helper.PodRequestsAndLimits(pod)
+	nodeCapacity := node.Status.Capacity
+	if len(node.Status.Allocatable) > 0 {
+		nodeCapacity = node.Status.Allocatable
+	}
+
+	// cpu/memory usage (and their RequestsSource baseline, used to damp spikes) apply to both
+	// resources at once, so fetch each only once per node rather than once per resource.
+	var cpuMilli, memBytes, baselineCPUMilli, baselineMemBytes int64
+	for _, name := range resourceNames {
+		if name != v1.ResourceCPU && name != v1.ResourceMemory {
+			continue
+		}
+		var err error
+		cpuMilli, memBytes, err = usageSource.NodeUsage(node, pods)
+		if err != nil {
+			glog.Infof("Error computing usage of node %#v, treating as idle: %v", node.Name, err)
+		}
+		baselineCPUMilli, baselineMemBytes, err = usage.RequestsSource{}.NodeUsage(node, pods)
 		if err != nil {
-			glog.Infof("Error computing resource usage of pod, ignoring: %#v", pod.Name)
+			glog.Infof("Error computing baseline usage of node %#v: %v", node.Name, err)
+		}
+		break
+	}
+
+	nodeUsage := api.ResourceThresholds{}
+	for _, name := range resourceNames {
+		capValue, ok := resourceCapacityValue(nodeCapacity, name)
+		if !ok {
 			continue
 		}
-		for name, quantity := range req {
-			if name == v1.ResourceCPU || name == v1.ResourceMemory {
-				if value, ok := totalReqs[name]; !ok {
-					totalReqs[name] = *quantity.Copy()
-				} else {
-					value.Add(quantity)
-					totalReqs[name] = value
+		switch name {
+		case v1.ResourceCPU:
+			rawUsage := api.Percentage(float64(cpuMilli) * 100 / capValue)
+			baseline := api.Percentage(float64(baselineCPUMilli) * 100 / capValue)
+			nodeUsage[v1.ResourceCPU] = dampUsageSpike(rawUsage, baseline, usageAvoidanceThreshold)
+		case v1.ResourceMemory:
+			rawUsage := api.Percentage(float64(memBytes) * 100 / capValue)
+			baseline := api.Percentage(float64(baselineMemBytes) * 100 / capValue)
+			nodeUsage[v1.ResourceMemory] = dampUsageSpike(rawUsage, baseline, usageAvoidanceThreshold)
+		case v1.ResourcePods:
+			nodeUsage[v1.ResourcePods] = api.Percentage(float64(len(pods)) * 100 / capValue)
+		default:
+			var total int64
+			for _, pod := range pods {
+				req, _, err := helper.PodRequestsAndLimits(pod)
+				if err != nil {
+					glog.Infof("Error computing resource usage of pod, ignoring: %#v", pod.Name)
+					continue
+				}
+				if quantity, ok := req[name]; ok {
+					total += quantity.Value()
 				}
 			}
+			nodeUsage[name] = api.Percentage(float64(total) * 100 / capValue)
 		}
 	}
-
-	nodeCapacity := node.Status.Capacity
-	if len(node.Status.Allocatable) > 0 {
-		nodeCapacity = node.Status.Allocatable
-	}
-
-	usage := api.ResourceThresholds{}
-	totalCPUReq := totalReqs[v1.ResourceCPU]
-	totalMemReq := totalReqs[v1.ResourceMemory]
-	totalPods := len(pods)
-	usage[v1.ResourceCPU] = api.Percentage((float64(totalCPUReq.MilliValue()) * 100) / float64(nodeCapacity.Cpu().MilliValue()))
-	usage[v1.ResourceMemory] = api.Percentage(float64(totalMemReq.Value()) / float64(nodeCapacity.Memory().Value()) * 100)
-	usage[v1.ResourcePods] = api.Percentage((float64(totalPods) * 100) / float64(nodeCapacity.Pods().Value()))
-	return usage, nonRemovablePods, bePods, bPods, gPods
+	return nodeUsage, nonRemovablePods, bePods, bPods, gPods
 }
@@ -0,0 +1,200 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategies
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/api/v1"
+
+	"github.com/kubernetes-incubator/descheduler/pkg/api"
+)
+
+const gpuResource = v1.ResourceName("nvidia.com/gpu")
+
+func lowNodeWithGPUUsage(name string, gpuUsagePercent api.Percentage) NodeUsageMap {
+	return NodeUsageMap{
+		node: &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					gpuResource: *resource.NewQuantity(4, resource.DecimalSI),
+				},
+			},
+		},
+		usage: api.ResourceThresholds{gpuResource: gpuUsagePercent},
+	}
+}
+
+func TestBestFitLowNodeRespectsExtendedResourceThreshold(t *testing.T) {
+	resourceNames := []v1.ResourceName{gpuResource}
+	thresholds := api.ResourceThresholds{gpuResource: 50}
+	// node already at 75% GPU usage (3 of 4 gpus); a pod requesting one more gpu would push
+	// it to 100%, which must be rejected even though the function never looks at cpu/memory.
+	lowNodes := []NodeUsageMap{lowNodeWithGPUUsage("gpu-node", 75)}
+	podUsage := map[v1.ResourceName]int64{gpuResource: 1}
+
+	if got := bestFitLowNode(lowNodes, thresholds, resourceNames, podUsage); got != nil {
+		t.Fatalf("expected no low node to fit, got %#v", got.node.Name)
+	}
+}
+
+func TestBestFitLowNodeRejectsNodeMissingResourceEntirely(t *testing.T) {
+	resourceNames := []v1.ResourceName{gpuResource}
+	thresholds := api.ResourceThresholds{gpuResource: 50}
+	// node has no nvidia.com/gpu entry in Allocatable at all (a plain, non-GPU node).
+	nonGPUNode := NodeUsageMap{
+		node: &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpu-only-node"},
+			Status:     v1.NodeStatus{Allocatable: v1.ResourceList{}},
+		},
+		usage: api.ResourceThresholds{},
+	}
+	podUsage := map[v1.ResourceName]int64{gpuResource: 1}
+
+	if got := bestFitLowNode([]NodeUsageMap{nonGPUNode}, thresholds, resourceNames, podUsage); got != nil {
+		t.Fatalf("expected a node with no gpu capacity to never fit a pod requesting a gpu, got %#v", got.node.Name)
+	}
+}
+
+func TestBestFitLowNodeAllowsNodeMissingUnrequestedResource(t *testing.T) {
+	resourceNames := []v1.ResourceName{gpuResource}
+	thresholds := api.ResourceThresholds{gpuResource: 50}
+	nonGPUNode := NodeUsageMap{
+		node: &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpu-only-node"},
+			Status:     v1.NodeStatus{Allocatable: v1.ResourceList{}},
+		},
+		usage: api.ResourceThresholds{},
+	}
+	// pod doesn't actually request the missing resource, so the node's lack of it shouldn't
+	// block the placement.
+	podUsage := map[v1.ResourceName]int64{}
+
+	if got := bestFitLowNode([]NodeUsageMap{nonGPUNode}, thresholds, resourceNames, podUsage); got == nil {
+		t.Fatalf("expected the node to fit a pod that doesn't request the missing resource")
+	}
+}
+
+func TestBestFitLowNodeAcceptsWithinThreshold(t *testing.T) {
+	resourceNames := []v1.ResourceName{gpuResource}
+	thresholds := api.ResourceThresholds{gpuResource: 50}
+	lowNodes := []NodeUsageMap{lowNodeWithGPUUsage("gpu-node", 0)}
+	podUsage := map[v1.ResourceName]int64{gpuResource: 1}
+
+	got := bestFitLowNode(lowNodes, thresholds, resourceNames, podUsage)
+	if got == nil {
+		t.Fatalf("expected a low node to fit a pod well within threshold")
+	}
+
+	projectPodOntoNode(got, resourceNames, podUsage)
+	if got.usage[gpuResource] != 25 {
+		t.Fatalf("expected projected gpu usage of 25%%, got %v", got.usage[gpuResource])
+	}
+}
+
+func nodeWithLabels(name string, labels map[string]string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestAssignNodesToPoolsFirstMatchingPoolWins(t *testing.T) {
+	gpuNode := nodeWithLabels("gpu-1", map[string]string{"hw": "gpu", "tier": "prod"})
+	prodNode := nodeWithLabels("prod-1", map[string]string{"tier": "prod"})
+	otherNode := nodeWithLabels("other-1", map[string]string{"tier": "dev"})
+	nodes := []*v1.Node{gpuNode, prodNode, otherNode}
+
+	pools := []api.NodePoolSpec{
+		{Name: "gpu-pool", Selector: metav1.LabelSelector{MatchLabels: map[string]string{"hw": "gpu"}}},
+		// overlaps with gpu-pool on gpuNode; gpu-pool listed first must win it.
+		{Name: "prod-pool", Selector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "prod"}}},
+	}
+
+	assignments, unassigned := assignNodesToPools(nodes, pools)
+	if len(assignments) != 2 {
+		t.Fatalf("expected 2 pool assignments, got %d", len(assignments))
+	}
+
+	gpuAssignment := assignments[0]
+	if gpuAssignment.pool.Name != "gpu-pool" || len(gpuAssignment.nodes) != 1 || gpuAssignment.nodes[0] != gpuNode {
+		t.Fatalf("expected gpu-pool to claim only gpu-1, got %#v", gpuAssignment)
+	}
+
+	prodAssignment := assignments[1]
+	if prodAssignment.pool.Name != "prod-pool" || len(prodAssignment.nodes) != 1 || prodAssignment.nodes[0] != prodNode {
+		t.Fatalf("expected prod-pool to claim only prod-1 (gpu-1 already claimed), got %#v", prodAssignment)
+	}
+
+	if len(unassigned) != 1 || unassigned[0] != otherNode {
+		t.Fatalf("expected other-1 to fall back unassigned, got %#v", unassigned)
+	}
+}
+
+func TestAssignNodesToPoolsSkipsInvalidSelector(t *testing.T) {
+	node := nodeWithLabels("n1", map[string]string{"tier": "prod"})
+	pools := []api.NodePoolSpec{
+		{Name: "broken-pool", Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "NotAnOperator"}}}},
+	}
+
+	assignments, unassigned := assignNodesToPools([]*v1.Node{node}, pools)
+	if len(assignments) != 0 {
+		t.Fatalf("expected the invalid selector to produce no assignment, got %#v", assignments)
+	}
+	if len(unassigned) != 1 || unassigned[0] != node {
+		t.Fatalf("expected the node to fall back unassigned when every pool selector is invalid, got %#v", unassigned)
+	}
+}
+
+func TestDampUsageSpikeIgnoresSmallDeltas(t *testing.T) {
+	if got := dampUsageSpike(55, 50, 10); got != 50 {
+		t.Fatalf("expected a 5-point delta under a 10-point threshold to be damped to the baseline 50, got %v", got)
+	}
+}
+
+func TestDampUsageSpikePassesThroughLargeDeltas(t *testing.T) {
+	if got := dampUsageSpike(80, 50, 10); got != 80 {
+		t.Fatalf("expected a 30-point delta over a 10-point threshold to pass through as 80, got %v", got)
+	}
+}
+
+func TestDampUsageSpikeDisabledByZeroThreshold(t *testing.T) {
+	if got := dampUsageSpike(55, 50, 0); got != 55 {
+		t.Fatalf("expected a zero threshold to disable damping entirely, got %v", got)
+	}
+}
+
+func TestOrderedEvictionCandidatesFallsBackToQoSThenPriorityOnUnknownType(t *testing.T) {
+	bePod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "be"}}
+	node := NodeUsageMap{bePods: []*v1.Pod{bePod}}
+
+	unknown := &api.PodEvictionOrder{Type: api.PodEvictionOrderType("NotARealOrder")}
+	got := orderedEvictionCandidates(node, unknown)
+	if len(got) != 1 || got[0] != bePod {
+		t.Fatalf("expected an unknown PodEvictionOrder.Type to fall back to QoSThenPriority, got %#v", got)
+	}
+}
+
+func TestOrderedEvictionCandidatesNilFallsBackToQoSThenPriority(t *testing.T) {
+	bePod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "be"}}
+	node := NodeUsageMap{bePods: []*v1.Pod{bePod}}
+
+	got := orderedEvictionCandidates(node, nil)
+	if len(got) != 1 || got[0] != bePod {
+		t.Fatalf("expected a nil PodEvictionOrder to fall back to QoSThenPriority, got %#v", got)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package usage provides pluggable sources of node and pod resource usage for strategies
+// that need more accurate load data than summed resource requests, such as LowNodeUtilization.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/api/v1"
+	helper "k8s.io/kubernetes/pkg/api/v1/resource"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset_generated/clientset"
+
+	"github.com/kubernetes-incubator/descheduler/pkg/api"
+)
+
+// Source abstracts how node and pod resource usage is measured. RequestsSource preserves the
+// historical behavior of summing pod resource requests; MetricsServerSource and
+// PrometheusSource derive usage from real, observed load instead.
+type Source interface {
+	// NodeUsage returns the node's CPU usage in milli-cores and memory usage in bytes. pods is
+	// the set of pods scheduled on node, and is only consulted by sources (like RequestsSource)
+	// that derive node usage from their pods rather than querying an external API.
+	NodeUsage(node *v1.Node, pods []*v1.Pod) (cpuMilli int64, memBytes int64, err error)
+	// PodUsage returns a single pod's CPU usage in milli-cores and memory usage in bytes.
+	PodUsage(pod *v1.Pod) (cpuMilli int64, memBytes int64, err error)
+}
+
+// NewSource builds the Source described by spec. A nil spec returns a RequestsSource, matching
+// the strategy's behavior before UsageSource existed.
+func NewSource(spec *api.UsageSource, metricsClient metricsclientset.Interface) (Source, error) {
+	if spec == nil {
+		return RequestsSource{}, nil
+	}
+	switch spec.Type {
+	case "", api.RequestsUsageSourceType:
+		return RequestsSource{}, nil
+	case api.MetricsServerUsageSourceType:
+		if metricsClient == nil {
+			return nil, fmt.Errorf("usage source type %q requires a metrics client, but DeschedulerServer was not configured with one", spec.Type)
+		}
+		return NewMetricsServerSource(metricsClient), nil
+	case api.PrometheusUsageSourceType:
+		return NewPrometheusSource(spec.PrometheusAddress, spec.PrometheusQueries, spec.Window)
+	default:
+		return nil, fmt.Errorf("unknown usage source type %q", spec.Type)
+	}
+}
+
+// RequestsSource derives usage from summed pod resource requests.
+type RequestsSource struct{}
+
+var _ Source = RequestsSource{}
+
+func (RequestsSource) NodeUsage(node *v1.Node, pods []*v1.Pod) (int64, int64, error) {
+	var cpuMilli, memBytes int64
+	for _, pod := range pods {
+		c, m, err := (RequestsSource{}).PodUsage(pod)
+		if err != nil {
+			continue
+		}
+		cpuMilli += c
+		memBytes += m
+	}
+	return cpuMilli, memBytes, nil
+}
+
+func (RequestsSource) PodUsage(pod *v1.Pod) (int64, int64, error) {
+	req, _, err := helper.PodRequestsAndLimits(pod)
+	if err != nil {
+		return 0, 0, err
+	}
+	cpu := req[v1.ResourceCPU]
+	mem := req[v1.ResourceMemory]
+	return cpu.MilliValue(), mem.Value(), nil
+}
+
+// MetricsServerSource derives usage from the metrics.k8s.io NodeMetrics/PodMetrics API.
+type MetricsServerSource struct {
+	client metricsclientset.Interface
+}
+
+var _ Source = &MetricsServerSource{}
+
+func NewMetricsServerSource(client metricsclientset.Interface) *MetricsServerSource {
+	return &MetricsServerSource{client: client}
+}
+
+func (m *MetricsServerSource) NodeUsage(node *v1.Node, pods []*v1.Pod) (int64, int64, error) {
+	nm, err := m.client.MetricsV1beta1().NodeMetricses().Get(node.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to fetch node metrics for %q: %v", node.Name, err)
+	}
+	cpu := nm.Usage[v1.ResourceCPU]
+	mem := nm.Usage[v1.ResourceMemory]
+	return cpu.MilliValue(), mem.Value(), nil
+}
+
+func (m *MetricsServerSource) PodUsage(pod *v1.Pod) (int64, int64, error) {
+	pm, err := m.client.MetricsV1beta1().PodMetricses(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to fetch pod metrics for %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	var cpuMilli, memBytes int64
+	for _, c := range pm.Containers {
+		cpuMilli += c.Usage.Cpu().MilliValue()
+		memBytes += c.Usage.Memory().Value()
+	}
+	return cpuMilli, memBytes, nil
+}
+
+// PrometheusSource derives node usage from operator-supplied PromQL query templates. Per-pod
+// usage is not supported; configure MetricsServerSource instead when eviction sizing needs to
+// reason about individual pods.
+type PrometheusSource struct {
+	api      promv1.API
+	cpuQuery string
+	memQuery string
+	window   string
+}
+
+var _ Source = &PrometheusSource{}
+
+func NewPrometheusSource(address string, queries map[v1.ResourceName]string, window string) (*PrometheusSource, error) {
+	client, err := promapi.NewClient(promapi.Config{Address: address})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create prometheus client for %q: %v", address, err)
+	}
+	if window == "" {
+		window = "5m"
+	}
+	return &PrometheusSource{
+		api:      promv1.NewAPI(client),
+		cpuQuery: queries[v1.ResourceCPU],
+		memQuery: queries[v1.ResourceMemory],
+		window:   window,
+	}, nil
+}
+
+func (p *PrometheusSource) NodeUsage(node *v1.Node, pods []*v1.Pod) (int64, int64, error) {
+	cpuCores, err := p.query(p.cpuQuery, node.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+	memBytes, err := p.query(p.memQuery, node.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(cpuCores * 1000), int64(memBytes), nil
+}
+
+func (p *PrometheusSource) PodUsage(pod *v1.Pod) (int64, int64, error) {
+	return 0, 0, fmt.Errorf("PrometheusSource does not support per-pod usage; configure MetricsServerSource for eviction sizing")
+}
+
+func (p *PrometheusSource) query(queryTemplate, nodeName string) (float64, error) {
+	if queryTemplate == "" {
+		return 0, fmt.Errorf("no prometheus query configured for this resource")
+	}
+	query := strings.NewReplacer("{{.NodeName}}", nodeName, "{{.Window}}", p.window).Replace(queryTemplate)
+	result, _, err := p.api.Query(context.Background(), query, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("prometheus query %q failed: %v", query, err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok || len(vector) == 0 {
+		return 0, fmt.Errorf("prometheus query %q returned no samples", query)
+	}
+	return float64(vector[0].Value), nil
+}